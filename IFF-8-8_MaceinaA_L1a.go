@@ -1,19 +1,32 @@
 package main
 
 import (
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
 	"encoding/json"
-	"fmt"
+	"flag"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 )
 
-const DataNumber = 30             // How much moto in json file
-const WorkerCount = 10            // How much worker routines to start
-const BufferSize = DataNumber / 2 // Size of DataMonitor internal buffer
-const Criteria = 26               // Select moto whose purchase value is less
+var (
+	workers  = flag.Int("workers", 10, "number of concurrent worker goroutines")
+	buffer   = flag.Int("buffer", 15, "capacity of the producer/consumer ring buffer")
+	criteria = flag.Int("criteria", 26, "select motos whose purchase rank is less than this value")
+	in       = flag.String("in", "IFF-8-8_MaceinaA_L1_dat_1.json", "input JSON file (ignored if -batch is set)")
+	out      = flag.String("out", "IFF-8-8_MaceinaA_L1_rez.txt", "output report file (ignored if -batch is set)")
+	batch    = flag.String("batch", "", `glob pattern of input JSON files to process concurrently, e.g. "data/*.json"; overrides -in/-out`)
+	stream   = flag.Bool("stream", false, "decode the input incrementally with json.Decoder and feed workers before the file finishes reading, for inputs too large to hold in memory at once")
+	dedupOn  = flag.Bool("dedup", false, "rank each unique moto only once, detecting duplicates across overlapping input files by a SHA-256 hash of their canonical JSON encoding")
+	format   = flag.String("format", "", "output format: table, json, csv, or recfile (default: infer from -out's extension, falling back to table)")
+)
 
 type (
 	Moto struct {
@@ -25,39 +38,110 @@ type (
 	MotoRank struct {
 		Moto Moto
 		Rank int
+		Hash [32]byte
 	}
 
 	DataMonitor struct {
-		Motos                 [BufferSize]Moto
+		Motos                 []Moto
+		Capacity              int
 		In, Out               int
 		Work, Space           *sync.Cond
 		WorkCount, SpaceCount int
 		InputLock, OutputLock sync.Mutex
 	}
-
-	SortedResultMonitor struct {
-		Motos [DataNumber]MotoRank
-		Count int
-		Lock  sync.Mutex
-	}
 )
 
 func main() {
-	dataMonitor := NewDataMonitor()
-	resultMonitor := NewSortedResultMonitor()
+	flag.Parse()
+
+	if *workers < 1 {
+		log.Fatalf("-workers must be at least 1, got %d", *workers)
+	}
+	if *buffer < 1 {
+		log.Fatalf("-buffer must be at least 1, got %d", *buffer)
+	}
+
+	if *batch != "" {
+		runBatch(*batch, *workers, *buffer, *criteria, *stream, *dedupOn, *format)
+		return
+	}
+
+	runPipeline(*in, *out, *workers, *buffer, *criteria, *stream, *dedupOn, *format)
+}
+
+// runBatch processes every file matched by pattern concurrently, one
+// pipeline per file, writing each result next to its input. When
+// dedupEnabled, a single dedup is shared across every file so a moto
+// repeated across overlapping inputs is still only ranked once.
+func runBatch(pattern string, workerCount, bufferSize, criteria int, stream, dedupEnabled bool, format string) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Fatalf("invalid -batch pattern %q: %v", pattern, err)
+	}
+	if len(files) == 0 {
+		log.Printf("no files matched -batch pattern %q", pattern)
+		return
+	}
+
+	var dd *dedup
+	if dedupEnabled {
+		dd = newDedup()
+	}
+
 	var wg sync.WaitGroup
-	wg.Add(WorkerCount)
+	wg.Add(len(files))
+	for _, file := range files {
+		go func(file string) {
+			defer wg.Done()
+			runPipelineWithDedup(file, batchReportPath(file), workerCount, bufferSize, criteria, stream, dd, format)
+		}(file)
+	}
+	wg.Wait()
+}
+
+// batchReportPath derives a per-file report path by swapping the input
+// file's extension for "_rez.txt".
+func batchReportPath(inPath string) string {
+	ext := filepath.Ext(inPath)
+	return strings.TrimSuffix(inPath, ext) + "_rez.txt"
+}
+
+func runPipeline(inPath, outPath string, workerCount, bufferSize, criteria int, stream, dedupEnabled bool, format string) {
+	var dd *dedup
+	if dedupEnabled {
+		dd = newDedup()
+	}
+	runPipelineWithDedup(inPath, outPath, workerCount, bufferSize, criteria, stream, dd, format)
+}
+
+// runPipelineWithDedup runs one pipeline over inPath. dd may be nil, in
+// which case every moto is ranked regardless of duplicates; otherwise it
+// is shared with the caller so duplicates can be detected across calls.
+func runPipelineWithDedup(inPath, outPath string, workerCount, bufferSize, criteria int, stream bool, dd *dedup, format string) {
+	dataMonitor := NewDataMonitor(bufferSize)
+	resultsCh := make(chan []MotoRank, workerCount)
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	startWorkers(dataMonitor, resultsCh, workerCount, criteria, dd, &wg)
+
+	resultWriter, _ := resultWriterFor(format, outPath)
+	_, needsInputData := resultWriter.(tableResultWriter)
+
+	var motos []Moto
+	if stream {
+		motos = streamData(inPath, dataMonitor, needsInputData)
+	} else {
+		motos = readData(inPath)
+		fillDataMonitor(motos, dataMonitor)
+	}
 
-	motos := readData("IFF-8-8_MaceinaA_L1_dat_1.json")
-	startWorkers(dataMonitor, resultMonitor, WorkerCount, &wg)
-	fillDataMonitor(&motos, dataMonitor)
 	wg.Wait()
-	writeData("IFF-8-8_MaceinaA_L1_rez.txt", &motos, resultMonitor)
+	close(resultsCh)
+	writeReport(outPath, format, motos, mergeSortedResults(resultsCh))
 }
 
-func NewSortedResultMonitor() *SortedResultMonitor { return &SortedResultMonitor{} }
-func NewDataMonitor() *DataMonitor {
-	monitor := DataMonitor{SpaceCount: BufferSize}
+func NewDataMonitor(bufferSize int) *DataMonitor {
+	monitor := DataMonitor{Motos: make([]Moto, bufferSize), Capacity: bufferSize, SpaceCount: bufferSize}
 	monitor.Work = sync.NewCond(&monitor.OutputLock)
 	monitor.Space = sync.NewCond(&monitor.InputLock)
 	return &monitor
@@ -69,7 +153,7 @@ func (m *DataMonitor) addItem(item Moto) {
 		m.Space.Wait()
 	}
 	m.Motos[m.In] = item
-	m.In = (m.In + 1) % BufferSize
+	m.In = (m.In + 1) % m.Capacity
 	m.SpaceCount--
 	m.InputLock.Unlock()
 
@@ -93,7 +177,7 @@ func (m *DataMonitor) removeItem() Moto {
 		return moto
 	}
 
-	m.Out = (m.Out + 1) % BufferSize
+	m.Out = (m.Out + 1) % m.Capacity
 	m.WorkCount--
 	m.OutputLock.Unlock()
 
@@ -109,77 +193,211 @@ func (m *Moto) BestMotoRank() int {
 	return time.Now().Year() - m.Date + int(m.Distance/1_000)
 }
 
-func fillDataMonitor(motos *[DataNumber]Moto, dataMonitor *DataMonitor) {
+func fillDataMonitor(motos []Moto, dataMonitor *DataMonitor) {
 	for _, moto := range motos {
 		dataMonitor.addItem(moto)
 	}
 	dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
 }
 
-func startWorkers(dataMonitor *DataMonitor, resultMonitor *SortedResultMonitor, workerCount int, wg *sync.WaitGroup) {
+func startWorkers(dataMonitor *DataMonitor, results chan<- []MotoRank, workerCount, criteria int, dd *dedup, wg *sync.WaitGroup) {
 	for i := 0; i < workerCount; i++ {
-		go worker(dataMonitor, resultMonitor, wg)
+		go worker(dataMonitor, results, criteria, dd, wg)
 	}
 }
 
-func worker(in *DataMonitor, out *SortedResultMonitor, wg *sync.WaitGroup) {
+// worker ranks motos until it sees <EndOfInput>, accumulating them in a
+// local slice with no shared lock on the hot path. On exit it sorts its
+// own slice once and hands it to results, where mergeSortedResults folds
+// every worker's sorted slice into the final order. When dd is non-nil,
+// a moto whose hash was already seen (by this or any other worker) is
+// skipped instead of ranked again.
+func worker(in *DataMonitor, results chan<- []MotoRank, criteria int, dd *dedup, wg *sync.WaitGroup) {
 	defer wg.Done()
+
+	var local []MotoRank
 	for {
 		moto := in.removeItem()
 		if moto.Manufacturer == "<EndOfInput>" {
 			break
 		}
 
+		hash := hashMoto(moto)
+		if dd != nil && !dd.markSeen(hash) {
+			continue
+		}
+
 		motoRank := moto.BestMotoRank()
-		if motoRank < Criteria {
-			moto := MotoRank{moto, motoRank}
-			out.addItemSorted(moto)
+		if motoRank < criteria {
+			local = append(local, MotoRank{Moto: moto, Rank: motoRank, Hash: hash})
 		}
 	}
+
+	slices.SortFunc(local, compareMotoRank)
+	results <- local
+}
+
+// dedup tracks the SHA-256 hashes of motos already ranked, so that
+// repeated or overlapping input files contribute each unique moto once.
+// Borrowed from syncthing's scanner, which uses the same block-hash
+// approach to skip re-transferring unchanged file chunks.
+type dedup struct {
+	seen map[[32]byte]struct{}
+	lock sync.Mutex
+}
+
+func newDedup() *dedup {
+	return &dedup{seen: make(map[[32]byte]struct{})}
 }
 
-// Sort by moto age ascending, and then by year descending
-func (m *SortedResultMonitor) addItemSorted(moto MotoRank) {
-	m.Lock.Lock()
-	i := m.Count - 1
-	for i >= 0 && (m.Motos[i].Rank > moto.Rank || (m.Motos[i].Rank == moto.Rank && m.Motos[i].Moto.Date < moto.Moto.Date)) {
-		m.Motos[i+1] = m.Motos[i]
-		i--
+// markSeen records hash and reports whether this is the first time it
+// has been seen. Safe for concurrent use.
+func (d *dedup) markSeen(hash [32]byte) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.seen[hash]; ok {
+		return false
 	}
-	m.Motos[i+1] = moto
-	m.Count++
-	m.Lock.Unlock()
+	d.seen[hash] = struct{}{}
+	return true
 }
 
-func readData(path string) [DataNumber]Moto {
+// hashMoto computes a SHA-256 hash over moto's canonical JSON encoding,
+// used to recognize the same moto submitted more than once.
+func hashMoto(moto Moto) [32]byte {
+	data, _ := json.Marshal(moto)
+	return sha256.Sum256(data)
+}
+
+// compareMotoRank orders by Rank ascending, then Date descending, then
+// Hash as a final tiebreak. Hash is a function of the moto's own fields
+// (see hashMoto), not of when or by which worker it was processed, so two
+// motos that tie on Rank and Date still compare the same way no matter
+// which worker ranked them or in what order their results arrived; two
+// ties are only left unresolved by Hash when the motos are byte-identical,
+// in which case any order between them is indistinguishable anyway.
+func compareMotoRank(a, b MotoRank) int {
+	if a.Rank != b.Rank {
+		return a.Rank - b.Rank
+	}
+	if a.Moto.Date != b.Moto.Date {
+		return b.Moto.Date - a.Moto.Date
+	}
+	return bytes.Compare(a.Hash[:], b.Hash[:])
+}
+
+// mergeSortedResults drains ch, the sorted per-worker slices produced by
+// worker, and k-way merges them into one fully sorted slice with a
+// min-heap over each slice's current head. This keeps the sort off the
+// hot path entirely, and because compareMotoRank is a total order over
+// the motos' own field values (see its doc comment), the merged result is
+// byte-identical across runs of the same input regardless of which
+// worker ranked which moto or the order their slices arrived on ch.
+func mergeSortedResults(ch <-chan []MotoRank) []MotoRank {
+	var total int
+	var cursors resultCursorHeap
+	for s := range ch {
+		total += len(s)
+		if len(s) > 0 {
+			cursors = append(cursors, resultCursor{slice: s})
+		}
+	}
+	heap.Init(&cursors)
+
+	merged := make([]MotoRank, 0, total)
+	for cursors.Len() > 0 {
+		cur := heap.Pop(&cursors).(resultCursor)
+		merged = append(merged, cur.slice[0])
+		if rest := cur.slice[1:]; len(rest) > 0 {
+			heap.Push(&cursors, resultCursor{slice: rest})
+		}
+	}
+	return merged
+}
+
+// resultCursor is one worker's remaining sorted slice.
+type resultCursor struct {
+	slice []MotoRank
+}
+
+type resultCursorHeap []resultCursor
+
+func (h resultCursorHeap) Len() int { return len(h) }
+func (h resultCursorHeap) Less(i, j int) bool {
+	return compareMotoRank(h[i].slice[0], h[j].slice[0]) < 0
+}
+func (h resultCursorHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *resultCursorHeap) Push(x any)   { *h = append(*h, x.(resultCursor)) }
+func (h *resultCursorHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+func readData(path string) []Moto {
 	data, _ := ioutil.ReadFile(path)
-	var motos [DataNumber]Moto
+	var motos []Moto
 	_ = json.Unmarshal(data, &motos)
 	return motos
 }
 
-func writeData(path string, inputData *[DataNumber]Moto, results *SortedResultMonitor) {
-	file, _ := os.Create(path)
+// streamData decodes path incrementally with json.Decoder, pushing each
+// Moto into dataMonitor as soon as it is parsed so that workers can start
+// consuming and ranking before the file has finished reading; peak
+// memory for the decode side is bounded by dataMonitor's buffer size,
+// not the input's length. needsInputData controls whether parsed motos
+// are also collected and returned for the report's input-data section
+// (only the table format uses it — see ResultWriter); when false they
+// are discarded right after being handed to dataMonitor, so -stream with
+// a non-table format never holds the full input in memory either.
+func streamData(path string, dataMonitor *DataMonitor, needsInputData bool) []Moto {
+	file, err := os.Open(path)
+	if err != nil {
+		dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
+		return nil
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	if _, err := dec.Token(); err != nil { // consume the opening '['
+		dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
+		return nil
+	}
+
+	var motos []Moto
+	for dec.More() {
+		var moto Moto
+		if err := dec.Decode(&moto); err != nil {
+			break
+		}
+		if needsInputData {
+			motos = append(motos, moto)
+		}
+		dataMonitor.addItem(moto)
+	}
+	dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
+	return motos
+}
+
+// writeReport creates outPath and writes results (and, for the table
+// format, inputData) to it using the ResultWriter selected by format or,
+// if format is empty, by outPath's extension.
+func writeReport(outPath, format string, inputData []Moto, results []MotoRank) {
+	file, err := os.Create(outPath)
+	if err != nil {
+		log.Printf("writeReport: %v", err)
+		return
+	}
 	defer file.Close()
 
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 42)+"\n")
-	_, _ = fmt.Fprintf(file, "┃%25s%16s\n", "INPUT DATA", "┃")
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 42)+"\n")
-	_, _ = fmt.Fprintf(file, "┃%-13s┃%10s┃%15s┃\n", "Manufacturer", "Date", "Distance")
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 42)+"\n")
-	for _, moto := range inputData {
-		_, _ = fmt.Fprintf(file, "┃%-13s┃%10d┃%15.2f┃\n", moto.Manufacturer, moto.Date, moto.Distance)
-	}
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 42)+"\n\n")
-
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 48)+"\n")
-	_, _ = fmt.Fprintf(file, "┃%29s%18s\n", "OUTPUT DATA", "┃")
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 48)+"\n")
-	_, _ = fmt.Fprintf(file, "┃%-13s┃%10s┃%15s┃%5s┃\n", "Manufacturer", "Date", "Distance", "Rank")
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 48)+"\n")
-	for i := 0; i < results.Count; i++ {
-		data := results.Motos[i]
-		_, _ = fmt.Fprintf(file, "┃%-13s┃%10d┃%15.2f┃%5d┃\n", data.Moto.Manufacturer, data.Moto.Date, data.Moto.Distance, data.Rank)
-	}
-	_, _ = fmt.Fprint(file, strings.Repeat("━", 48)+"\n")
+	writer, err := resultWriterFor(format, outPath)
+	if err != nil {
+		log.Printf("writeReport: %v", err)
+		return
+	}
+	if err := writer.WriteResults(file, inputData, results); err != nil {
+		log.Printf("writeReport: %v", err)
+	}
 }
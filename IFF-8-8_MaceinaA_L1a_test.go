@@ -0,0 +1,379 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// insertSorted mirrors the addItemSorted method removed in this change:
+// a shifted insertion that keeps the slice sorted after every item.
+// Kept here only to benchmark against the append+SortFunc replacement.
+func insertSorted(motos []MotoRank, item MotoRank) []MotoRank {
+	motos = append(motos, MotoRank{})
+	i := len(motos) - 2
+	for i >= 0 && compareMotoRank(motos[i], item) > 0 {
+		motos[i+1] = motos[i]
+		i--
+	}
+	motos[i+1] = item
+	return motos
+}
+
+func randomMotoRanks(n int) []MotoRank {
+	r := rand.New(rand.NewSource(1))
+	motos := make([]MotoRank, n)
+	for i := range motos {
+		motos[i] = MotoRank{Moto: Moto{Date: r.Intn(2000)}, Rank: r.Intn(100)}
+	}
+	return motos
+}
+
+func BenchmarkInsertSorted(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		items := randomMotoRanks(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var motos []MotoRank
+				for _, item := range items {
+					motos = insertSorted(motos, item)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppendThenSort(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		items := randomMotoRanks(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				motos := make([]MotoRank, 0, len(items))
+				motos = append(motos, items...)
+				slices.SortFunc(motos, compareMotoRank)
+			}
+		})
+	}
+}
+
+// sharedMutexInsertAll mirrors the SortedResultMonitor design this change
+// replaces: every worker inserts into one shared sorted slice behind a
+// single mutex.
+func sharedMutexInsertAll(workerCount int, items []MotoRank) []MotoRank {
+	var mu sync.Mutex
+	var shared []MotoRank
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for _, chunk := range splitEvenly(items, workerCount) {
+		go func(chunk []MotoRank) {
+			defer wg.Done()
+			for _, item := range chunk {
+				mu.Lock()
+				shared = insertSorted(shared, item)
+				mu.Unlock()
+			}
+		}(chunk)
+	}
+	wg.Wait()
+	return shared
+}
+
+// localSortThenMergeAll mirrors worker/mergeSortedResults: every worker
+// sorts its own chunk locally, with no shared lock, and a k-way merge
+// combines the sorted chunks afterward.
+func localSortThenMergeAll(workerCount int, items []MotoRank) []MotoRank {
+	ch := make(chan []MotoRank, workerCount)
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for _, chunk := range splitEvenly(items, workerCount) {
+		go func(chunk []MotoRank) {
+			defer wg.Done()
+			local := append([]MotoRank(nil), chunk...)
+			slices.SortFunc(local, compareMotoRank)
+			ch <- local
+		}(chunk)
+	}
+	wg.Wait()
+	close(ch)
+	return mergeSortedResults(ch)
+}
+
+func splitEvenly(items []MotoRank, n int) [][]MotoRank {
+	chunkSize := (len(items) + n - 1) / n
+	chunks := make([][]MotoRank, n)
+	for i := range chunks {
+		start := min(i*chunkSize, len(items))
+		end := min(start+chunkSize, len(items))
+		chunks[i] = items[start:end]
+	}
+	return chunks
+}
+
+func BenchmarkSharedMutexInsert(b *testing.B) {
+	const workerCount = 10
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		items := randomMotoRanks(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sharedMutexInsertAll(workerCount, items)
+			}
+		})
+	}
+}
+
+func BenchmarkLocalSortThenMerge(b *testing.B) {
+	const workerCount = 10
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		items := randomMotoRanks(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				localSortThenMergeAll(workerCount, items)
+			}
+		})
+	}
+}
+
+func TestMergeSortedResults_OrdersByRankThenDateDescending(t *testing.T) {
+	ch := make(chan []MotoRank, 2)
+	ch <- []MotoRank{{Moto: Moto{Date: 2015}, Rank: 2}, {Moto: Moto{Date: 2010}, Rank: 5}}
+	ch <- []MotoRank{{Moto: Moto{Date: 2012}, Rank: 2}, {Moto: Moto{Date: 2000}, Rank: 8}}
+	close(ch)
+
+	merged := mergeSortedResults(ch)
+
+	want := []int{2015, 2012, 2010, 2000}
+	if len(merged) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(merged), len(want), merged)
+	}
+	for i, date := range want {
+		if merged[i].Moto.Date != date {
+			t.Fatalf("merged[%d].Moto.Date = %d, want %d (full slice: %+v)", i, merged[i].Moto.Date, date, merged)
+		}
+	}
+}
+
+// TestMergeSortedResults_TiesBreakDeterministicallyByHash guards against a
+// regression where motos tied on (Rank, Date) were ordered by the results
+// channel's arrival order, which depends on the DataMonitor's sync.Cond
+// race and so is not reproducible across runs. Chunks are handed to
+// mergeSortedResults in a different rotation each run, standing in for
+// that scheduling nondeterminism, and the merged order must not change.
+func TestMergeSortedResults_TiesBreakDeterministicallyByHash(t *testing.T) {
+	var tied []MotoRank
+	for _, manufacturer := range []string{"A", "B", "C", "D", "E", "F"} {
+		moto := Moto{Manufacturer: manufacturer, Date: 2010, Distance: 5000}
+		tied = append(tied, MotoRank{Moto: moto, Rank: moto.BestMotoRank(), Hash: hashMoto(moto)})
+	}
+
+	var want []MotoRank
+	for run := 0; run < 20; run++ {
+		chunks := splitEvenly(tied, 3)
+		rotated := append(append([][]MotoRank{}, chunks[run%3:]...), chunks[:run%3]...)
+
+		ch := make(chan []MotoRank, len(rotated))
+		for _, chunk := range rotated {
+			local := append([]MotoRank(nil), chunk...)
+			slices.SortFunc(local, compareMotoRank)
+			ch <- local
+		}
+		close(ch)
+
+		got := mergeSortedResults(ch)
+		if run == 0 {
+			want = got
+			continue
+		}
+		if len(got) != len(want) {
+			t.Fatalf("run %d: got %d results, want %d", run, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Hash != want[i].Hash {
+				t.Fatalf("run %d: order diverged at index %d:\ngot:  %+v\nwant: %+v", run, i, got, want)
+			}
+		}
+	}
+}
+
+// TestWorker_TiedRecordsMergeDeterministicallyAcrossRuns reproduces the
+// maintainer's empirical finding (200 tied motos across 8 workers, 30
+// runs, produced multiple orderings) with real worker goroutines racing
+// over a shared DataMonitor, and asserts the merged order is identical
+// every run.
+func TestWorker_TiedRecordsMergeDeterministicallyAcrossRuns(t *testing.T) {
+	manufacturers := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+
+	runOnce := func() []MotoRank {
+		dataMonitor := NewDataMonitor(4)
+		results := make(chan []MotoRank, 4)
+		var wg sync.WaitGroup
+		wg.Add(4)
+		for i := 0; i < 4; i++ {
+			go worker(dataMonitor, results, 1000, nil, &wg)
+		}
+		for _, manufacturer := range manufacturers {
+			dataMonitor.addItem(Moto{Manufacturer: manufacturer, Date: 2010, Distance: 5000})
+		}
+		dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
+		wg.Wait()
+		close(results)
+		return mergeSortedResults(results)
+	}
+
+	want := runOnce()
+	for run := 1; run < 10; run++ {
+		got := runOnce()
+		if len(got) != len(want) {
+			t.Fatalf("run %d: got %d results, want %d", run, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Hash != want[i].Hash {
+				t.Fatalf("run %d: merge order diverged at index %d:\ngot:  %+v\nwant: %+v", run, i, got, want)
+			}
+		}
+	}
+}
+
+func TestWorker_DedupRanksDuplicateMotoOnce(t *testing.T) {
+	dataMonitor := NewDataMonitor(4)
+	results := make(chan []MotoRank, 2)
+	dd := newDedup()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go worker(dataMonitor, results, 1000, dd, &wg)
+	go worker(dataMonitor, results, 1000, dd, &wg)
+
+	moto := Moto{Manufacturer: "Honda", Date: 2015, Distance: 5000}
+	for i := 0; i < 3; i++ { // as if submitted by 3 overlapping input files
+		dataMonitor.addItem(moto)
+	}
+	dataMonitor.addItem(Moto{Manufacturer: "<EndOfInput>"})
+	wg.Wait()
+	close(results)
+
+	merged := mergeSortedResults(results)
+	if len(merged) != 1 {
+		t.Fatalf("got %d ranked motos for 3 duplicate submissions, want 1: %+v", len(merged), merged)
+	}
+}
+
+func TestStreamData_FeedsDataMonitorWhileDecoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motos.json")
+	const jsonData = `[
+		{"manufacturer": "Honda", "date": 2015, "distance": 12000},
+		{"manufacturer": "Yamaha", "date": 2018, "distance": 4000}
+	]`
+	if err := os.WriteFile(path, []byte(jsonData), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	dataMonitor := NewDataMonitor(1)
+	var got []Moto
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			moto := dataMonitor.removeItem()
+			if moto.Manufacturer == "<EndOfInput>" {
+				return
+			}
+			got = append(got, moto)
+		}
+	}()
+
+	motos := streamData(path, dataMonitor, true)
+	wg.Wait()
+
+	if len(motos) != 2 || len(got) != 2 {
+		t.Fatalf("streamData returned %d motos, dataMonitor fed %d, want 2 each", len(motos), len(got))
+	}
+	if got[0].Manufacturer != "Honda" || got[1].Manufacturer != "Yamaha" {
+		t.Fatalf("unexpected motos fed to dataMonitor: %+v", got)
+	}
+}
+
+// TestStreamData_SkipsCollectionWhenInputDataNotNeeded guards against
+// streamData holding the whole input in memory for formats that never
+// read it back (see ResultWriter): with needsInputData false it must
+// still feed every moto to dataMonitor, but return nil instead of
+// accumulating them.
+func TestStreamData_SkipsCollectionWhenInputDataNotNeeded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motos.json")
+	const jsonData = `[
+		{"manufacturer": "Honda", "date": 2015, "distance": 12000},
+		{"manufacturer": "Yamaha", "date": 2018, "distance": 4000}
+	]`
+	if err := os.WriteFile(path, []byte(jsonData), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	dataMonitor := NewDataMonitor(1)
+	var fedCount int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			moto := dataMonitor.removeItem()
+			if moto.Manufacturer == "<EndOfInput>" {
+				return
+			}
+			fedCount++
+		}
+	}()
+
+	motos := streamData(path, dataMonitor, false)
+	wg.Wait()
+
+	if motos != nil {
+		t.Fatalf("streamData(needsInputData=false) returned %d motos, want none", len(motos))
+	}
+	if fedCount != 2 {
+		t.Fatalf("dataMonitor fed %d motos, want 2", fedCount)
+	}
+}
+
+func TestBatchReportPath_SwapsExtensionForRezTxt(t *testing.T) {
+	cases := map[string]string{
+		"data/input.json":    "data/input_rez.txt",
+		"input.json":         "input_rez.txt",
+		"no_extension":       "no_extension_rez.txt",
+		"data/nested.a.json": "data/nested.a_rez.txt",
+	}
+	for in, want := range cases {
+		if got := batchReportPath(in); got != want {
+			t.Errorf("batchReportPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunBatch_WritesOneReportPerMatchedFile(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{"a.json", "b.json"}
+	for _, name := range inputs {
+		data := `[{"manufacturer": "Honda", "date": 2015, "distance": 12000}]`
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s): %v", name, err)
+		}
+	}
+
+	runBatch(filepath.Join(dir, "*.json"), 2, 4, 1000, false, false, "")
+
+	for _, name := range inputs {
+		inPath := filepath.Join(dir, name)
+		reportPath := batchReportPath(inPath)
+		report, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatalf("report for %s not written: %v", name, err)
+		}
+		if !strings.Contains(string(report), "Honda") {
+			t.Errorf("report %s missing expected moto: %s", reportPath, report)
+		}
+	}
+}
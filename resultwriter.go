@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ResultWriter renders one pipeline run's results to w. Implementations
+// for the table, json, csv, and recfile formats live alongside this
+// file; inputData is only used by the table format's legacy two-section
+// report and may be ignored by the others.
+type ResultWriter interface {
+	WriteResults(w io.Writer, inputData []Moto, results []MotoRank) error
+}
+
+// resultWriterFor resolves a ResultWriter for format, falling back to
+// outPath's extension when format is empty.
+func resultWriterFor(format, outPath string) (ResultWriter, error) {
+	if format == "" {
+		format = formatFromExt(outPath)
+	}
+
+	switch format {
+	case "table":
+		return tableResultWriter{}, nil
+	case "json":
+		return jsonResultWriter{}, nil
+	case "csv":
+		return csvResultWriter{}, nil
+	case "recfile":
+		return recfileResultWriter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -format %q", format)
+	}
+}
+
+func formatFromExt(path string) string {
+	switch filepath.Ext(path) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	case ".rec":
+		return "recfile"
+	default:
+		return "table"
+	}
+}
+
+// writer wraps an io.Writer and a *error so a sequence of writes can be
+// issued without checking err after each one; the first failure sticks
+// and every write after it becomes a no-op.
+type writer struct {
+	w   io.Writer
+	err error
+}
+
+func (f *writer) printf(format string, args ...any) {
+	if f.err != nil {
+		return
+	}
+	_, f.err = fmt.Fprintf(f.w, format, args...)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"strconv"
+)
+
+// csvResultWriter renders results as CSV, one row per MotoRank.
+type csvResultWriter struct{}
+
+func (csvResultWriter) WriteResults(w io.Writer, _ []Moto, results []MotoRank) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"Manufacturer", "Date", "Distance", "Rank", "Hash"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row := []string{
+			r.Moto.Manufacturer,
+			strconv.Itoa(r.Moto.Date),
+			strconv.FormatFloat(r.Moto.Distance, 'f', 2, 64),
+			strconv.Itoa(r.Rank),
+			hex.EncodeToString(r.Hash[:]),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+)
+
+// jsonResultWriter renders results as a JSON array, encoding one
+// MotoRank at a time instead of marshaling the whole slice at once, so
+// memory use stays flat for very large result sets.
+type jsonResultWriter struct{}
+
+type motoRankJSON struct {
+	Manufacturer string  `json:"manufacturer"`
+	Date         int     `json:"date"`
+	Distance     float64 `json:"distance"`
+	Rank         int     `json:"rank"`
+	Hash         string  `json:"hash"`
+}
+
+func (jsonResultWriter) WriteResults(w io.Writer, _ []Moto, results []MotoRank) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for i, r := range results {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		record := motoRankJSON{
+			Manufacturer: r.Moto.Manufacturer,
+			Date:         r.Moto.Date,
+			Distance:     r.Moto.Distance,
+			Rank:         r.Rank,
+			Hash:         hex.EncodeToString(r.Hash[:]),
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
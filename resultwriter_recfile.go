@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+)
+
+// recfileResultWriter renders results in the GNU recutils recfile
+// format: blank-line-separated records of "Field: value" lines.
+type recfileResultWriter struct{}
+
+func (recfileResultWriter) WriteResults(w io.Writer, _ []Moto, results []MotoRank) error {
+	f := &writer{w: w}
+
+	for i, r := range results {
+		if i > 0 {
+			f.printf("\n")
+		}
+		f.printf("Manufacturer: %s\n", r.Moto.Manufacturer)
+		f.printf("Date: %d\n", r.Moto.Date)
+		f.printf("Distance: %.2f\n", r.Moto.Distance)
+		f.printf("Rank: %d\n", r.Rank)
+		f.printf("Hash: %s\n", hex.EncodeToString(r.Hash[:]))
+	}
+
+	return f.err
+}
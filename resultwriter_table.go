@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// tableResultWriter renders the original box-drawing report: an input
+// data section followed by the ranked output data section.
+type tableResultWriter struct{}
+
+func (tableResultWriter) WriteResults(w io.Writer, inputData []Moto, results []MotoRank) error {
+	f := &writer{w: w}
+
+	f.printf(strings.Repeat("━", 42) + "\n")
+	f.printf("┃%25s%16s\n", "INPUT DATA", "┃")
+	f.printf(strings.Repeat("━", 42) + "\n")
+	f.printf("┃%-13s┃%10s┃%15s┃\n", "Manufacturer", "Date", "Distance")
+	f.printf(strings.Repeat("━", 42) + "\n")
+	for _, moto := range inputData {
+		f.printf("┃%-13s┃%10d┃%15.2f┃\n", moto.Manufacturer, moto.Date, moto.Distance)
+	}
+	f.printf(strings.Repeat("━", 42) + "\n\n")
+
+	f.printf(strings.Repeat("━", 59) + "\n")
+	f.printf("┃%36s%22s\n", "OUTPUT DATA", "┃")
+	f.printf(strings.Repeat("━", 59) + "\n")
+	f.printf("┃%-13s┃%10s┃%15s┃%5s┃%10s┃\n", "Manufacturer", "Date", "Distance", "Rank", "Hash")
+	f.printf(strings.Repeat("━", 59) + "\n")
+	for _, data := range results {
+		f.printf("┃%-13s┃%10d┃%15.2f┃%5d┃%10s┃\n", data.Moto.Manufacturer, data.Moto.Date, data.Moto.Distance, data.Rank, hex.EncodeToString(data.Hash[:])[:8])
+	}
+	f.printf(strings.Repeat("━", 59) + "\n")
+
+	return f.err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleResults() []MotoRank {
+	return []MotoRank{
+		{Moto: Moto{Manufacturer: "Honda", Date: 2015, Distance: 12000}, Rank: 8, Hash: hashMoto(Moto{Manufacturer: "Honda", Date: 2015, Distance: 12000})},
+		{Moto: Moto{Manufacturer: "Yamaha", Date: 2018, Distance: 4000}, Rank: 5, Hash: hashMoto(Moto{Manufacturer: "Yamaha", Date: 2018, Distance: 4000})},
+	}
+}
+
+func TestResultWriterFor_InfersFromExtension(t *testing.T) {
+	cases := map[string]ResultWriter{
+		"out.json": jsonResultWriter{},
+		"out.csv":  csvResultWriter{},
+		"out.rec":  recfileResultWriter{},
+		"out.txt":  tableResultWriter{},
+		"out":      tableResultWriter{},
+	}
+	for path, want := range cases {
+		got, err := resultWriterFor("", path)
+		if err != nil {
+			t.Fatalf("resultWriterFor(%q): %v", path, err)
+		}
+		if got != want {
+			t.Errorf("resultWriterFor(%q) = %T, want %T", path, got, want)
+		}
+	}
+}
+
+func TestResultWriterFor_ExplicitFormatOverridesExtension(t *testing.T) {
+	got, err := resultWriterFor("csv", "out.json")
+	if err != nil {
+		t.Fatalf("resultWriterFor: %v", err)
+	}
+	if _, ok := got.(csvResultWriter); !ok {
+		t.Fatalf("resultWriterFor(\"csv\", ...) = %T, want csvResultWriter", got)
+	}
+}
+
+func TestResultWriterFor_UnknownFormatErrors(t *testing.T) {
+	if _, err := resultWriterFor("yaml", "out.yaml"); err == nil {
+		t.Fatal("resultWriterFor with unknown format: got nil error, want non-nil")
+	}
+}
+
+func TestTableResultWriter_WriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (tableResultWriter{}).WriteResults(&buf, []Moto{{Manufacturer: "Honda", Date: 2015, Distance: 12000}}, sampleResults()); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"INPUT DATA", "OUTPUT DATA", "Honda", "Yamaha"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestJSONResultWriter_WriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonResultWriter{}).WriteResults(&buf, nil, sampleResults()); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	var decoded []motoRankJSON
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decoding written JSON: %v\noutput: %s", err, buf.String())
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("got %d records, want 2", len(decoded))
+	}
+	if decoded[0].Manufacturer != "Honda" || decoded[1].Manufacturer != "Yamaha" {
+		t.Fatalf("unexpected records: %+v", decoded)
+	}
+}
+
+func TestCSVResultWriter_WriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (csvResultWriter{}).WriteResults(&buf, nil, sampleResults()); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 { // header + 2 rows
+		t.Fatalf("got %d lines, want 3 (header + 2 rows):\n%s", len(lines), buf.String())
+	}
+	if lines[0] != "Manufacturer,Date,Distance,Rank,Hash" {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "Honda,2015,12000.00,8,") {
+		t.Errorf("unexpected first row: %q", lines[1])
+	}
+}
+
+func TestRecfileResultWriter_WriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (recfileResultWriter{}).WriteResults(&buf, nil, sampleResults()); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	records := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n\n")
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(records), buf.String())
+	}
+	if !strings.Contains(records[0], "Manufacturer: Honda") || !strings.Contains(records[0], "Rank: 8") {
+		t.Errorf("unexpected first record: %q", records[0])
+	}
+	if !strings.Contains(records[1], "Manufacturer: Yamaha") {
+		t.Errorf("unexpected second record: %q", records[1])
+	}
+}